@@ -0,0 +1,58 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See the LICENSE file in builder/azure for license information.
+
+package arm
+
+import (
+	"github.com/Azure/azure-sdk-for-go/arm/compute"
+	"github.com/Azure/azure-sdk-for-go/arm/marketplaceordering"
+	"github.com/Azure/azure-sdk-for-go/arm/network"
+	"github.com/Azure/go-autorest/autorest"
+)
+
+// AzureClient bundles the Azure Resource Manager clients used across the
+// build, all authorized with the same OAuth token.
+type AzureClient struct {
+	VirtualMachinesClient       compute.VirtualMachinesClient
+	ImagesClient                compute.ImagesClient
+	GalleryImageVersionsClient  compute.GalleryImageVersionsClient
+	InterfacesClient            network.InterfacesClient
+	MarketplaceAgreementsClient marketplaceordering.MarketplaceAgreementsClient
+}
+
+// NewAzureClient authenticates against Azure Active Directory using
+// whichever strategy Config selected (client secret, client certificate, or
+// Managed Service Identity) and returns the set of ARM clients needed to
+// provision and publish the temp build VM.
+func NewAzureClient(c *Config) (*AzureClient, error) {
+	token, err := c.getServicePrincipalToken()
+	if err != nil {
+		return nil, err
+	}
+
+	authorizer := autorest.NewBearerAuthorizer(token)
+	endpoint := c.cloudEnvironment.ResourceManagerEndpoint
+
+	virtualMachinesClient := compute.NewVirtualMachinesClientWithBaseURI(endpoint, c.SubscriptionID)
+	virtualMachinesClient.Authorizer = authorizer
+
+	imagesClient := compute.NewImagesClientWithBaseURI(endpoint, c.SubscriptionID)
+	imagesClient.Authorizer = authorizer
+
+	galleryImageVersionsClient := compute.NewGalleryImageVersionsClientWithBaseURI(endpoint, c.SubscriptionID)
+	galleryImageVersionsClient.Authorizer = authorizer
+
+	interfacesClient := network.NewInterfacesClientWithBaseURI(endpoint, c.SubscriptionID)
+	interfacesClient.Authorizer = authorizer
+
+	marketplaceAgreementsClient := marketplaceordering.NewMarketplaceAgreementsClientWithBaseURI(endpoint, c.SubscriptionID)
+	marketplaceAgreementsClient.Authorizer = authorizer
+
+	return &AzureClient{
+		VirtualMachinesClient:       virtualMachinesClient,
+		ImagesClient:                imagesClient,
+		GalleryImageVersionsClient:  galleryImageVersionsClient,
+		InterfacesClient:            interfacesClient,
+		MarketplaceAgreementsClient: marketplaceAgreementsClient,
+	}, nil
+}
@@ -0,0 +1,59 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See the LICENSE file in builder/azure for license information.
+
+package arm
+
+import (
+	"context"
+
+	"github.com/mitchellh/multistep"
+
+	"github.com/hashicorp/packer/builder/azure/common/constants"
+	"github.com/hashicorp/packer/packer"
+)
+
+// Builder implements packer.Builder and builds Azure custom images from an
+// ARM-based temp build VM.
+type Builder struct {
+	config *Config
+	runner multistep.Runner
+}
+
+func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
+	c, warnings, err := newConfig(raws...)
+	if err != nil {
+		return warnings, err
+	}
+	b.config = c
+	return warnings, nil
+}
+
+func (b *Builder) Run(ctx context.Context, ui packer.Ui, hook packer.Hook) (packer.Artifact, error) {
+	azureClient, err := NewAzureClient(b.config)
+	if err != nil {
+		return nil, err
+	}
+
+	var steps = []multistep.Step{
+		NewStepCheckMarketplaceAgreement(azureClient, ui),
+		NewStepCreatePrimaryNic(azureClient, ui),
+		NewStepCreateAdditionalNics(azureClient, ui),
+		NewStepDeployTemplate(azureClient, ui),
+		NewStepCaptureManagedImage(azureClient, ui),
+		NewStepPublishToSharedImageGallery(azureClient, ui),
+	}
+
+	state := new(multistep.BasicStateBag)
+	state.Put(constants.ConfigKey, b.config)
+	state.Put("hook", hook)
+	state.Put("ui", ui)
+
+	b.runner = packer.NewRunner(steps, b.config.PackerConfig, ui)
+	b.runner.Run(ctx, state)
+
+	if rawErr, ok := state.GetOk(constants.Error); ok {
+		return nil, rawErr.(error)
+	}
+
+	return nil, nil
+}
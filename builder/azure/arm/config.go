@@ -13,11 +13,13 @@ import (
 	"fmt"
 	"io/ioutil"
 	"math/big"
+	"net/http"
 	"regexp"
 	"strings"
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/arm/compute"
+	"github.com/Azure/go-autorest/autorest/adal"
 	"github.com/Azure/go-autorest/autorest/azure"
 	"github.com/Azure/go-autorest/autorest/to"
 	"github.com/masterzen/winrm"
@@ -38,22 +40,28 @@ const (
 	DefaultImageVersion         = "latest"
 	DefaultUserName             = "packer"
 	DefaultVMSize               = "Standard_A1"
+
+	msiAvailableEndpoint = "http://169.254.169.254/metadata/identity/oauth2/token"
 )
 
 var (
 	reCaptureContainerName = regexp.MustCompile("^[a-z0-9][a-z0-9\\-]{2,62}$")
 	reCaptureNamePrefix    = regexp.MustCompile("^[A-Za-z0-9][A-Za-z0-9_\\-\\.]{0,23}$")
+	reSshCaPrincipal       = regexp.MustCompile("^[A-Za-z0-9][A-Za-z0-9_\\-]*$")
 )
 
 type Config struct {
 	common.PackerConfig `mapstructure:",squash"`
 
 	// Authentication via OAUTH
-	ClientID       string `mapstructure:"client_id"`
-	ClientSecret   string `mapstructure:"client_secret"`
-	ObjectID       string `mapstructure:"object_id"`
-	TenantID       string `mapstructure:"tenant_id"`
-	SubscriptionID string `mapstructure:"subscription_id"`
+	ClientID           string `mapstructure:"client_id"`
+	ClientSecret       string `mapstructure:"client_secret"`
+	ClientCertPath     string `mapstructure:"client_certificate_path"`
+	ClientCertPassword string `mapstructure:"client_certificate_password"`
+	ObjectID           string `mapstructure:"object_id"`
+	TenantID           string `mapstructure:"tenant_id"`
+	SubscriptionID     string `mapstructure:"subscription_id"`
+	UseMSI             bool   `mapstructure:"use_msi"`
 
 	// Capture
 	CaptureNamePrefix    string `mapstructure:"capture_name_prefix"`
@@ -70,6 +78,12 @@ type Config struct {
 	CustomManagedImageName              string `mapstructure:"custom_managed_image_name"`
 	customManagedImageID                string
 
+	PlanInfo PlanInformation `mapstructure:"plan_info"`
+
+	SharedImageGallery            SharedImageGallery            `mapstructure:"shared_image_gallery"`
+	SharedImageGalleryDestination SharedImageGalleryDestination `mapstructure:"shared_image_gallery_destination"`
+	sharedImageGalleryImageID     string
+
 	Location string `mapstructure:"location"`
 	VMSize   string `mapstructure:"vm_size"`
 
@@ -91,6 +105,8 @@ type Config struct {
 	VirtualNetworkResourceGroupName string `mapstructure:"virtual_network_resource_group_name"`
 	CustomDataFile                  string `mapstructure:"custom_data_file"`
 	customData                      string
+	EnableAcceleratedNetworking     bool      `mapstructure:"enable_accelerated_networking"`
+	AdditionalNetworkInterfaces     []NicSpec `mapstructure:"additional_network_interfaces"`
 
 	// OS
 	OSType       string `mapstructure:"os_type"`
@@ -109,10 +125,14 @@ type Config struct {
 	tmpWinRMCertificateUrl string
 
 	useDeviceLogin bool
+	useMSI         bool
 
 	// Authentication with the VM via SSH
-	sshAuthorizedKey string
-	sshPrivateKey    string
+	SSHCAPrivateKey        string   `mapstructure:"ssh_ca_private_key"`
+	SSHCASigningPrincipals []string `mapstructure:"ssh_ca_signing_principals"`
+	SSHCAValidity          string   `mapstructure:"ssh_ca_validity"`
+	sshAuthorizedKey       string
+	sshPrivateKey          string
 
 	// Authentication with the VM via WinRM
 	winrmCertificate string
@@ -127,14 +147,177 @@ type keyVaultCertificate struct {
 	Password string `json:"password,omitempty"`
 }
 
+// SharedImageGallery identifies a Shared Image Gallery image version to use
+// as the source image for the build, as an alternative to image_url,
+// image_publisher/image_offer/image_sku, or a custom managed image.
+type SharedImageGallery struct {
+	Subscription  string `mapstructure:"subscription"`
+	ResourceGroup string `mapstructure:"resource_group"`
+	GalleryName   string `mapstructure:"gallery_name"`
+	ImageName     string `mapstructure:"image_name"`
+	ImageVersion  string `mapstructure:"image_version"`
+}
+
+// SharedImageGalleryDestination describes a Shared Image Gallery image
+// version that the managed image produced by the build should be
+// replicated into, and the regions that should receive a replica.
+type SharedImageGalleryDestination struct {
+	SigDestinationSubscription       string   `mapstructure:"subscription"`
+	SigDestinationResourceGroup      string   `mapstructure:"resource_group"`
+	SigDestinationGalleryName        string   `mapstructure:"gallery_name"`
+	SigDestinationImageName          string   `mapstructure:"image_name"`
+	SigDestinationImageVersion       string   `mapstructure:"image_version"`
+	SigDestinationReplicationRegions []string `mapstructure:"replication_regions"`
+	SigDestinationReplicaCount       int32    `mapstructure:"replica_count"`
+}
+
+// NicSpec describes an additional network interface to attach to the temp
+// build VM, beyond the single default NIC the builder otherwise creates.
+// SubnetName and NetworkSecurityGroup are both bare resource names, resolved
+// against the same vnet resource group as virtual_network_subnet_name (see
+// toSubnetID and toNetworkSecurityGroupID).
+type NicSpec struct {
+	SubnetName                  string `mapstructure:"subnet_name"`
+	NetworkSecurityGroup        string `mapstructure:"network_security_group"`
+	EnableAcceleratedNetworking bool   `mapstructure:"enable_accelerated_networking"`
+}
+
+// acceleratedNetworkingVMSizes is the set of vm_size values known to support
+// SR-IOV and therefore accelerated networking.
+var acceleratedNetworkingVMSizes = map[string]bool{
+	"Standard_D3_v2":   true,
+	"Standard_D12_v2":  true,
+	"Standard_D4_v2":   true,
+	"Standard_D13_v2":  true,
+	"Standard_D5_v2":   true,
+	"Standard_D14_v2":  true,
+	"Standard_D15_v2":  true,
+	"Standard_DS3_v2":  true,
+	"Standard_DS12_v2": true,
+	"Standard_DS4_v2":  true,
+	"Standard_DS13_v2": true,
+	"Standard_DS5_v2":  true,
+	"Standard_DS14_v2": true,
+	"Standard_DS15_v2": true,
+	"Standard_D3_v3":   true,
+	"Standard_D4_v3":   true,
+	"Standard_D8_v3":   true,
+	"Standard_D16_v3":  true,
+	"Standard_D32_v3":  true,
+	"Standard_D64_v3":  true,
+	"Standard_F4":      true,
+	"Standard_F8":      true,
+	"Standard_F16":     true,
+	"Standard_F4s":     true,
+	"Standard_F8s":     true,
+	"Standard_F16s":    true,
+}
+
+func (c *Config) isSharedImageGallery() bool {
+	return c.SharedImageGallery.Subscription != ""
+}
+
+func (c *Config) isPublishToSharedImageGallery() bool {
+	return c.SharedImageGalleryDestination.SigDestinationGalleryName != ""
+}
+
+func (c *Config) toSharedImageGalleryID() string {
+	return fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Compute/galleries/%s/images/%s/versions/%s",
+		c.SharedImageGallery.Subscription,
+		c.SharedImageGallery.ResourceGroup,
+		c.SharedImageGallery.GalleryName,
+		c.SharedImageGallery.ImageName,
+		c.SharedImageGallery.ImageVersion)
+}
+
+// setSharedImageGalleryImageID resolves the shared_image_gallery source, if
+// any, to the full image version resource ID that the temp build VM is
+// deployed from (see toVirtualMachine).
+func setSharedImageGalleryImageID(c *Config) {
+	if c.isSharedImageGallery() {
+		c.sharedImageGalleryImageID = c.toSharedImageGalleryID()
+	}
+}
+
+func (c *Config) isCustomManagedImage() bool {
+	return c.CustomManagedImageName != ""
+}
+
+func (c *Config) toCustomManagedImageID() string {
+	return fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Compute/images/%s",
+		c.SubscriptionID, c.CustomManagedImageResourceGroupName, c.CustomManagedImageName)
+}
+
+// setCustomManagedImageID resolves the custom_managed_image_name source, if
+// any, to the full image resource ID that the temp build VM is deployed
+// from (see toVirtualMachine).
+func setCustomManagedImageID(c *Config) {
+	if c.isCustomManagedImage() {
+		c.customManagedImageID = c.toCustomManagedImageID()
+	}
+}
+
 func (c *Config) toVMID() string {
 	return fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Compute/virtualMachines/%s", c.SubscriptionID, c.tmpResourceGroupName, c.tmpComputeName)
 }
 
+// toSubnetID resolves a bare subnet name, as given in
+// additional_network_interfaces or virtual_network_subnet_name, to the full
+// ARM resource ID required by the network interface create API.
+func (c *Config) toSubnetID(subnetName string) string {
+	vnetResourceGroup := c.VirtualNetworkResourceGroupName
+	if vnetResourceGroup == "" {
+		vnetResourceGroup = c.ResourceGroupName
+	}
+
+	return fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Network/virtualNetworks/%s/subnets/%s",
+		c.SubscriptionID, vnetResourceGroup, c.VirtualNetworkName, subnetName)
+}
+
+// toNetworkSecurityGroupID resolves a bare network security group name, as
+// given in additional_network_interfaces, to the full ARM resource ID
+// required by the network interface create API. It follows the same
+// resource-group convention as toSubnetID.
+func (c *Config) toNetworkSecurityGroupID(nsgName string) string {
+	nsgResourceGroup := c.VirtualNetworkResourceGroupName
+	if nsgResourceGroup == "" {
+		nsgResourceGroup = c.ResourceGroupName
+	}
+
+	return fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Network/networkSecurityGroups/%s",
+		c.SubscriptionID, nsgResourceGroup, nsgName)
+}
+
 func (c *Config) isManagedImage() bool {
 	return c.ManagedImageName != ""
 }
 
+// PlanInformation identifies the Marketplace plan of a third-party image
+// (e.g. CentOS from Rogue Wave, or a CIS-hardened image) that requires
+// legal terms to be accepted before a VM can be built from it.
+type PlanInformation struct {
+	PlanName      string `mapstructure:"name"`
+	PlanPublisher string `mapstructure:"publisher"`
+	PlanProduct   string `mapstructure:"product"`
+	AutoAccept    bool   `mapstructure:"auto_accept"`
+}
+
+func (c *Config) hasPlanInfo() bool {
+	return c.PlanInfo.PlanName != "" || c.PlanInfo.PlanPublisher != "" || c.PlanInfo.PlanProduct != ""
+}
+
+func (c *Config) toVMPlan() *compute.Plan {
+	if !c.hasPlanInfo() {
+		return nil
+	}
+
+	return &compute.Plan{
+		Name:      to.StringPtr(c.PlanInfo.PlanName),
+		Publisher: to.StringPtr(c.PlanInfo.PlanPublisher),
+		Product:   to.StringPtr(c.PlanInfo.PlanProduct),
+	}
+}
+
 func (c *Config) toVirtualMachineCaptureParameters() *compute.VirtualMachineCaptureParameters {
 	return &compute.VirtualMachineCaptureParameters{
 		DestinationContainerName: &c.CaptureContainerName,
@@ -152,6 +335,7 @@ func (c *Config) toImageParameters() *compute.Image {
 		},
 		Location: to.StringPtr(c.Location),
 		Tags:     &c.AzureTags,
+		Plan:     c.toVMPlan(),
 	}
 }
 
@@ -215,6 +399,57 @@ func (c *Config) createCertificate() (string, error) {
 	return base64.StdEncoding.EncodeToString(bytes), nil
 }
 
+// loadClientCertificate reads and decodes the PFX file at ClientCertPath,
+// returning the private key and certificate used to build a service
+// principal token via adal.NewServicePrincipalTokenFromCertificate.
+func (c *Config) loadClientCertificate() (*rsa.PrivateKey, *x509.Certificate, error) {
+	pfxContent, err := ioutil.ReadFile(c.ClientCertPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to read client_certificate_path %q: %s", c.ClientCertPath, err)
+	}
+
+	privateKey, certificate, err := pkcs12.Decode(pfxContent, c.ClientCertPassword)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to decode client_certificate_path as PFX: %s", err)
+	}
+
+	rsaPrivateKey, ok := privateKey.(*rsa.PrivateKey)
+	if !ok {
+		return nil, nil, fmt.Errorf("The private key in client_certificate_path must be an RSA key")
+	}
+
+	return rsaPrivateKey, certificate, nil
+}
+
+// getServicePrincipalToken builds the OAuth token used to authorize the
+// Azure SDK clients, picking the strategy selected during config
+// validation: Managed Service Identity, a client certificate, or a client
+// secret. useDeviceLogin is not consulted; a config that selected device
+// login falls through to the client secret path.
+func (c *Config) getServicePrincipalToken() (*adal.ServicePrincipalToken, error) {
+	resource := c.cloudEnvironment.ResourceManagerEndpoint
+
+	if c.useMSI {
+		return adal.NewServicePrincipalTokenFromMSI(msiAvailableEndpoint, resource)
+	}
+
+	oauthConfig, err := adal.NewOAuthConfig(c.cloudEnvironment.ActiveDirectoryEndpoint, c.TenantID)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create OAuth config: %s", err)
+	}
+
+	if c.ClientCertPath != "" {
+		privateKey, certificate, err := c.loadClientCertificate()
+		if err != nil {
+			return nil, err
+		}
+
+		return adal.NewServicePrincipalTokenFromCertificate(*oauthConfig, c.ClientID, certificate, privateKey, resource)
+	}
+
+	return adal.NewServicePrincipalToken(*oauthConfig, c.ClientID, c.ClientSecret, resource)
+}
+
 func newConfig(raws ...interface{}) (*Config, []string, error) {
 	var c Config
 
@@ -230,6 +465,8 @@ func newConfig(raws ...interface{}) (*Config, []string, error) {
 	provideDefaultValues(&c)
 	setRuntimeValues(&c)
 	setUserNamePassword(&c)
+	setSharedImageGalleryImageID(&c)
+	setCustomManagedImageID(&c)
 	err = setCloudEnvironment(&c)
 	if err != nil {
 		return nil, nil, err
@@ -301,6 +538,59 @@ func setSshValues(c *Config) error {
 		c.sshPrivateKey = sshKeyPair.PrivateKey()
 	}
 
+	if c.SSHCAPrivateKey != "" {
+		if err := signSshAuthorizedKey(c); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// signSshAuthorizedKey signs the ephemeral SSH public key in
+// c.sshAuthorizedKey with the configured SSH CA, replacing it with the
+// resulting OpenSSH user certificate. Shops that already run an SSH CA
+// (Vault, Smallstep, etc.) can then trust the CA rather than persisting
+// per-build public keys.
+func signSshAuthorizedKey(c *Config) error {
+	publicKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(c.sshAuthorizedKey))
+	if err != nil {
+		return fmt.Errorf("Failed to parse the ephemeral SSH public key for signing: %s", err)
+	}
+
+	caPrivateKeyBytes, err := ioutil.ReadFile(c.SSHCAPrivateKey)
+	if err != nil {
+		return fmt.Errorf("Failed to read ssh_ca_private_key: %s", err)
+	}
+
+	caSigner, err := ssh.ParsePrivateKey(caPrivateKeyBytes)
+	if err != nil {
+		return fmt.Errorf("Failed to parse ssh_ca_private_key: %s", err)
+	}
+
+	validity := 1 * time.Hour
+	if c.SSHCAValidity != "" {
+		validity, err = time.ParseDuration(c.SSHCAValidity)
+		if err != nil {
+			return fmt.Errorf("Failed to parse ssh_ca_validity: %s", err)
+		}
+	}
+
+	now := time.Now()
+	cert := &ssh.Certificate{
+		Key:             publicKey,
+		Serial:          uint64(now.Unix()),
+		CertType:        ssh.UserCert,
+		ValidPrincipals: c.SSHCASigningPrincipals,
+		ValidAfter:      uint64(now.Add(-5 * time.Minute).Unix()),
+		ValidBefore:     uint64(now.Add(validity).Unix()),
+	}
+
+	if err := cert.SignCert(rand.Reader, caSigner); err != nil {
+		return fmt.Errorf("Failed to sign SSH user certificate with ssh_ca_private_key: %s", err)
+	}
+
+	c.sshAuthorizedKey = string(ssh.MarshalAuthorizedKey(cert))
 	return nil
 }
 
@@ -384,6 +674,26 @@ func setCloudEnvironment(c *Config) error {
 	return err
 }
 
+// isMSIAvailable reports whether the Azure Instance Metadata Service is
+// reachable, which is only true when running on an Azure VM.
+func isMSIAvailable() bool {
+	client := &http.Client{Timeout: 2 * time.Second}
+
+	req, err := http.NewRequest("GET", msiAvailableEndpoint, nil)
+	if err != nil {
+		return false
+	}
+	req.Header.Set("Metadata", "true")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+
+	return true
+}
+
 func setCustomData(c *Config) error {
 	if c.CustomDataFile == "" {
 		return nil
@@ -410,6 +720,10 @@ func provideDefaultValues(c *Config) {
 	if c.CloudEnvironmentName == "" {
 		c.CloudEnvironmentName = DefaultCloudEnvironmentName
 	}
+
+	if c.SharedImageGalleryDestination.SigDestinationReplicaCount < 1 {
+		c.SharedImageGalleryDestination.SigDestinationReplicaCount = 1
+	}
 }
 
 func assertTagProperties(c *Config, errs *packer.MultiError) {
@@ -431,6 +745,25 @@ func assertRequiredParametersSet(c *Config, errs *packer.MultiError) {
 	/////////////////////////////////////////////
 	// Authentication via OAUTH
 
+	// Check if Managed Service Identity should be used.
+	//
+	// MSI is enabled explicitly via use_msi, or auto-detected when the user
+	// only defines SubscriptionID and not ClientID, ClientSecret, and
+	// TenantID, and the IMDS endpoint is reachable (i.e. Packer is itself
+	// running on an Azure VM).
+	isUseMSI := func(c *Config) bool {
+		if c.UseMSI {
+			return true
+		}
+
+		onlySubscriptionSet := c.SubscriptionID != "" &&
+			c.ClientID == "" &&
+			c.ClientSecret == "" &&
+			c.TenantID == ""
+
+		return onlySubscriptionSet && isMSIAvailable()
+	}
+
 	// Check if device login is being asked for, and is allowed.
 	//
 	// Device login is enabled if the user only defines SubscriptionID and not
@@ -450,15 +783,25 @@ func assertRequiredParametersSet(c *Config, errs *packer.MultiError) {
 			c.TenantID == ""
 	}
 
-	if isUseDeviceLogin(c) {
+	if isUseMSI(c) {
+		// MSI takes precedence over device login on every OS, including
+		// Windows, since it never requires an interactive sign-in.
+		c.useMSI = true
+
+		if c.SubscriptionID == "" {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("A subscription_id must be specified"))
+		}
+	} else if isUseDeviceLogin(c) {
 		c.useDeviceLogin = true
 	} else {
 		if c.ClientID == "" {
 			errs = packer.MultiErrorAppend(errs, fmt.Errorf("A client_id must be specified"))
 		}
 
-		if c.ClientSecret == "" {
-			errs = packer.MultiErrorAppend(errs, fmt.Errorf("A client_secret must be specified"))
+		if c.ClientSecret == "" && c.ClientCertPath == "" {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("A client_secret or client_certificate_path must be specified"))
+		} else if c.ClientSecret != "" && c.ClientCertPath != "" {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("Only one of client_secret or client_certificate_path may be specified"))
 		}
 
 		if c.SubscriptionID == "" {
@@ -519,14 +862,31 @@ func assertRequiredParametersSet(c *Config, errs *packer.MultiError) {
 	isImageUrl := c.ImageUrl != ""
 	isCustomManagedImage := c.CustomManagedImageName != "" || c.CustomManagedImageResourceGroupName != ""
 	isPlatformImage := c.ImagePublisher != "" || c.ImageOffer != "" || c.ImageSku != ""
+	isSharedImageGallery := c.isSharedImageGallery()
 
-	countSourceInputs := toInt(isImageUrl) + toInt(isCustomManagedImage) + toInt(isPlatformImage)
+	countSourceInputs := toInt(isImageUrl) + toInt(isCustomManagedImage) + toInt(isPlatformImage) + toInt(isSharedImageGallery)
 
 	if countSourceInputs > 1 {
-		errs = packer.MultiErrorAppend(errs, fmt.Errorf("Specify either a VHD (image_url), Image Reference (image_publisher, image_offer, image_sku) or a Managed Disk (custom_managed_disk_image_name, custom_managed_disk_resource_group_name"))
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("Specify either a VHD (image_url), Image Reference (image_publisher, image_offer, image_sku), a Managed Disk (custom_managed_image_name, custom_managed_image_resource_group_name) or a Shared Image Gallery source (shared_image_gallery)"))
 	}
 
-	if c.ImageUrl == "" && c.CustomManagedImageName == "" {
+	if isSharedImageGallery {
+		if c.SharedImageGallery.ResourceGroup == "" {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("A shared_image_gallery.resource_group must be specified"))
+		}
+		if c.SharedImageGallery.GalleryName == "" {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("A shared_image_gallery.gallery_name must be specified"))
+		}
+		if c.SharedImageGallery.ImageName == "" {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("A shared_image_gallery.image_name must be specified"))
+		}
+		if c.SharedImageGallery.ImageVersion == "" {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("A shared_image_gallery.image_version must be specified"))
+		}
+		if c.ManagedImageResourceGroupName == "" || c.ManagedImageName == "" {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("A managed_image_resource_group_name and managed_image_name must be specified when building from a shared_image_gallery source"))
+		}
+	} else if c.ImageUrl == "" && c.CustomManagedImageName == "" {
 		if c.ImagePublisher == "" {
 			errs = packer.MultiErrorAppend(errs, fmt.Errorf("An image_publisher must be specified"))
 		}
@@ -559,6 +919,37 @@ func assertRequiredParametersSet(c *Config, errs *packer.MultiError) {
 		errs = packer.MultiErrorAppend(errs, fmt.Errorf("A location must be specified"))
 	}
 
+	/////////////////////////////////////////////
+	// Plan / Marketplace image
+	if c.hasPlanInfo() {
+		if c.PlanInfo.PlanName == "" || c.PlanInfo.PlanPublisher == "" || c.PlanInfo.PlanProduct == "" {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("plan_info requires plan_info.name, plan_info.publisher, and plan_info.product to all be specified"))
+		}
+	}
+
+	/////////////////////////////////////////////
+	// Shared Image Gallery publishing
+	if c.isPublishToSharedImageGallery() {
+		if c.SharedImageGalleryDestination.SigDestinationSubscription == "" {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("A shared_image_gallery_destination.subscription must be specified"))
+		}
+		if c.SharedImageGalleryDestination.SigDestinationResourceGroup == "" {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("A shared_image_gallery_destination.resource_group must be specified"))
+		}
+		if c.SharedImageGalleryDestination.SigDestinationImageName == "" {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("A shared_image_gallery_destination.image_name must be specified"))
+		}
+		if c.SharedImageGalleryDestination.SigDestinationImageVersion == "" {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("A shared_image_gallery_destination.image_version must be specified"))
+		}
+		if len(c.SharedImageGalleryDestination.SigDestinationReplicationRegions) == 0 {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("At least one region must be specified in shared_image_gallery_destination.replication_regions"))
+		}
+		if !c.isManagedImage() {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("A managed_image_name and managed_image_resource_group_name must be specified when publishing to a shared_image_gallery_destination"))
+		}
+	}
+
 	/////////////////////////////////////////////
 	// Deployment
 	xor := func(a, b bool) bool {
@@ -578,11 +969,59 @@ func assertRequiredParametersSet(c *Config, errs *packer.MultiError) {
 		}
 	}
 
-	if c.VirtualNetworkName == "" && c.VirtualNetworkResourceGroupName != "" {
-		errs = packer.MultiErrorAppend(errs, fmt.Errorf("If virtual_network_resource_group_name is specified, so must virtual_network_name"))
+	// The primary NIC is always created as its own network.Interface
+	// resource (see StepCreatePrimaryNic) rather than relying on an
+	// ARM-template-embedded default vnet/subnet, so virtual_network_name is
+	// required unconditionally, not just when one of the other
+	// virtual-network-related options is set.
+	if c.VirtualNetworkName == "" {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("A virtual_network_name must be specified"))
 	}
-	if c.VirtualNetworkName == "" && c.VirtualNetworkSubnetName != "" {
-		errs = packer.MultiErrorAppend(errs, fmt.Errorf("If virtual_network_subnet_name is specified, so must virtual_network_name"))
+	if c.VirtualNetworkSubnetName == "" {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("A virtual_network_subnet_name must be specified"))
+	}
+
+	if c.EnableAcceleratedNetworking && !acceleratedNetworkingVMSizes[c.VMSize] {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("enable_accelerated_networking was set, but vm_size %q does not support accelerated networking", c.VMSize))
+	}
+
+	for i, nic := range c.AdditionalNetworkInterfaces {
+		if nic.SubnetName == "" {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("additional_network_interfaces[%d] must specify a subnet_name", i))
+		}
+
+		if nic.EnableAcceleratedNetworking && !acceleratedNetworkingVMSizes[c.VMSize] {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("additional_network_interfaces[%d] has enable_accelerated_networking set, but vm_size %q does not support accelerated networking", i, c.VMSize))
+		}
+	}
+
+	/////////////////////////////////////////////
+	// Authentication with the VM via SSH
+	if c.SSHCAPrivateKey != "" {
+		caPrivateKeyBytes, err := ioutil.ReadFile(c.SSHCAPrivateKey)
+		if err != nil {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("Failed to read ssh_ca_private_key: %s", err))
+		} else if _, err := ssh.ParsePrivateKey(caPrivateKeyBytes); err != nil {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("ssh_ca_private_key does not contain a valid private key: %s", err))
+		}
+
+		if len(c.SSHCASigningPrincipals) == 0 {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("At least one principal must be specified in ssh_ca_signing_principals when ssh_ca_private_key is set"))
+		}
+
+		for _, principal := range c.SSHCASigningPrincipals {
+			if !reSshCaPrincipal.MatchString(principal) {
+				errs = packer.MultiErrorAppend(errs, fmt.Errorf("The ssh_ca_signing_principals entry %q must satisfy the regular expression %q.", principal, reSshCaPrincipal.String()))
+			}
+		}
+
+		if c.SSHCAValidity != "" {
+			if _, err := time.ParseDuration(c.SSHCAValidity); err != nil {
+				errs = packer.MultiErrorAppend(errs, fmt.Errorf("ssh_ca_validity must be a valid duration: %s", err))
+			}
+		}
+	} else if len(c.SSHCASigningPrincipals) > 0 {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("ssh_ca_signing_principals was specified without ssh_ca_private_key"))
 	}
 
 	/////////////////////////////////////////////
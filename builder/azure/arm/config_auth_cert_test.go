@@ -0,0 +1,118 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See the LICENSE file in builder/azure for license information.
+
+package arm
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/azure"
+
+	"github.com/hashicorp/packer/builder/azure/pkcs12"
+)
+
+func writeTestClientCertificate(t *testing.T, password string) (path string, privateKey *rsa.PrivateKey) {
+	t.Helper()
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate private key: %s", err)
+	}
+
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		t.Fatalf("failed to generate serial number: %s", err)
+	}
+
+	template := x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               pkix.Name{CommonName: "packer-test"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+	}
+
+	derBytes, err := x509.CreateCertificate(rand.Reader, &template, &template, &privateKey.PublicKey, privateKey)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %s", err)
+	}
+
+	pfxBytes, err := pkcs12.Encode(derBytes, privateKey, password)
+	if err != nil {
+		t.Fatalf("failed to encode PFX: %s", err)
+	}
+
+	f, err := ioutil.TempFile("", "client-cert")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %s", err)
+	}
+	if _, err := f.Write(pfxBytes); err != nil {
+		t.Fatalf("failed to write PFX: %s", err)
+	}
+	f.Close()
+
+	return f.Name(), privateKey
+}
+
+func TestLoadClientCertificate(t *testing.T) {
+	const password = "s3cr3t"
+	certPath, privateKey := writeTestClientCertificate(t, password)
+	defer os.Remove(certPath)
+
+	c := &Config{ClientCertPath: certPath, ClientCertPassword: password}
+
+	loadedKey, cert, err := c.loadClientCertificate()
+	if err != nil {
+		t.Fatalf("loadClientCertificate failed: %s", err)
+	}
+	if cert.Subject.CommonName != "packer-test" {
+		t.Errorf("expected CommonName packer-test, got %q", cert.Subject.CommonName)
+	}
+	if loadedKey.N.Cmp(privateKey.N) != 0 {
+		t.Errorf("loaded private key does not match the one used to create the certificate")
+	}
+}
+
+func TestLoadClientCertificate_wrongPassword(t *testing.T) {
+	certPath, _ := writeTestClientCertificate(t, "s3cr3t")
+	defer os.Remove(certPath)
+
+	c := &Config{ClientCertPath: certPath, ClientCertPassword: "wrong"}
+
+	if _, _, err := c.loadClientCertificate(); err == nil {
+		t.Fatal("expected an error decoding the PFX with the wrong password, got nil")
+	}
+}
+
+func TestGetServicePrincipalToken_clientCertificate(t *testing.T) {
+	const password = "s3cr3t"
+	certPath, _ := writeTestClientCertificate(t, password)
+	defer os.Remove(certPath)
+
+	env := azure.PublicCloud
+	c := &Config{
+		ClientID:           "11111111-1111-1111-1111-111111111111",
+		ClientCertPath:     certPath,
+		ClientCertPassword: password,
+		TenantID:           "22222222-2222-2222-2222-222222222222",
+		cloudEnvironment:   &env,
+	}
+
+	token, err := c.getServicePrincipalToken()
+	if err != nil {
+		t.Fatalf("expected a certificate-backed token, got error: %s", err)
+	}
+	if token == nil {
+		t.Fatal("expected a non-nil service principal token")
+	}
+}
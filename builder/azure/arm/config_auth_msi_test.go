@@ -0,0 +1,68 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See the LICENSE file in builder/azure for license information.
+
+package arm
+
+import (
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/hashicorp/packer/packer"
+)
+
+func TestGetServicePrincipalToken_msi(t *testing.T) {
+	env := azure.PublicCloud
+	c := &Config{
+		useMSI:           true,
+		cloudEnvironment: &env,
+	}
+
+	token, err := c.getServicePrincipalToken()
+	if err != nil {
+		t.Fatalf("expected an MSI-backed token, got error: %s", err)
+	}
+	if token == nil {
+		t.Fatal("expected a non-nil service principal token")
+	}
+}
+
+func TestAssertRequiredParametersSet_useMsiRequiresSubscriptionID(t *testing.T) {
+	c := &Config{UseMSI: true}
+
+	// assertRequiredParametersSet appends into errs.Errors in place, so
+	// (like newConfig) seed a non-nil *MultiError before calling it.
+	errs := packer.MultiErrorAppend(nil)
+	assertRequiredParametersSet(c, errs)
+
+	if len(errs.Errors) == 0 {
+		t.Fatal("expected an error when use_msi is true but subscription_id is empty")
+	}
+
+	found := false
+	for _, err := range errs.Errors {
+		if err.Error() == "A subscription_id must be specified" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("errors = %v, want one complaining about a missing subscription_id", errs.Errors)
+	}
+}
+
+func TestGetServicePrincipalToken_clientSecret(t *testing.T) {
+	env := azure.PublicCloud
+	c := &Config{
+		ClientID:         "11111111-1111-1111-1111-111111111111",
+		ClientSecret:     "super-secret",
+		TenantID:         "22222222-2222-2222-2222-222222222222",
+		cloudEnvironment: &env,
+	}
+
+	token, err := c.getServicePrincipalToken()
+	if err != nil {
+		t.Fatalf("expected a client-secret-backed token, got error: %s", err)
+	}
+	if token == nil {
+		t.Fatal("expected a non-nil service principal token")
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See the LICENSE file in builder/azure for license information.
+
+package arm
+
+import (
+	"testing"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+func TestToSubnetID(t *testing.T) {
+	cases := []struct {
+		name              string
+		vnetResourceGroup string
+		resourceGroup     string
+		wantResourceGroup string
+	}{
+		{"falls back to resource_group_name", "", "packer-rg", "packer-rg"},
+		{"prefers virtual_network_resource_group_name", "vnet-rg", "packer-rg", "vnet-rg"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := &Config{}
+			c.SubscriptionID = "sub-id"
+			c.ResourceGroupName = tc.resourceGroup
+			c.VirtualNetworkResourceGroupName = tc.vnetResourceGroup
+			c.VirtualNetworkName = "my-vnet"
+
+			want := "/subscriptions/sub-id/resourceGroups/" + tc.wantResourceGroup +
+				"/providers/Microsoft.Network/virtualNetworks/my-vnet/subnets/my-subnet"
+			if got := c.toSubnetID("my-subnet"); got != want {
+				t.Errorf("toSubnetID() = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestToNetworkSecurityGroupID(t *testing.T) {
+	cases := []struct {
+		name              string
+		vnetResourceGroup string
+		resourceGroup     string
+		wantResourceGroup string
+	}{
+		{"falls back to resource_group_name", "", "packer-rg", "packer-rg"},
+		{"prefers virtual_network_resource_group_name", "vnet-rg", "packer-rg", "vnet-rg"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := &Config{}
+			c.SubscriptionID = "sub-id"
+			c.ResourceGroupName = tc.resourceGroup
+			c.VirtualNetworkResourceGroupName = tc.vnetResourceGroup
+
+			want := "/subscriptions/sub-id/resourceGroups/" + tc.wantResourceGroup +
+				"/providers/Microsoft.Network/networkSecurityGroups/my-nsg"
+			if got := c.toNetworkSecurityGroupID("my-nsg"); got != want {
+				t.Errorf("toNetworkSecurityGroupID() = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestAssertRequiredParametersSet_virtualNetworkRequiredForPrimaryNic(t *testing.T) {
+	// StepCreatePrimaryNic always creates the primary NIC as its own
+	// resource now, so virtual_network_name/virtual_network_subnet_name can
+	// no longer be left unset the way an ARM-template-embedded default vnet
+	// used to allow.
+	c := &Config{}
+
+	errs := packer.MultiErrorAppend(nil)
+	assertRequiredParametersSet(c, errs)
+
+	wantMessages := map[string]bool{
+		"A virtual_network_name must be specified":        false,
+		"A virtual_network_subnet_name must be specified": false,
+	}
+	for _, err := range errs.Errors {
+		if _, ok := wantMessages[err.Error()]; ok {
+			wantMessages[err.Error()] = true
+		}
+	}
+	for msg, found := range wantMessages {
+		if !found {
+			t.Errorf("expected an error %q, got %v", msg, errs.Errors)
+		}
+	}
+}
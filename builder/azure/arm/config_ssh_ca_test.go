@@ -0,0 +1,120 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See the LICENSE file in builder/azure for license information.
+
+package arm
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func writeTestSshCAPrivateKey(t *testing.T) (path string, signer ssh.Signer) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate CA key: %s", err)
+	}
+
+	pemBytes := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	})
+
+	f, err := ioutil.TempFile("", "ssh-ca-key")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %s", err)
+	}
+	if _, err := f.Write(pemBytes); err != nil {
+		t.Fatalf("failed to write CA key: %s", err)
+	}
+	f.Close()
+
+	signer, err = ssh.ParsePrivateKey(pemBytes)
+	if err != nil {
+		t.Fatalf("failed to parse generated CA key: %s", err)
+	}
+
+	return f.Name(), signer
+}
+
+func TestSignSshAuthorizedKey(t *testing.T) {
+	caPath, caSigner := writeTestSshCAPrivateKey(t)
+	defer os.Remove(caPath)
+
+	hostKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate host key: %s", err)
+	}
+	hostPublicKey, err := ssh.NewPublicKey(&hostKey.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to convert host key to an ssh.PublicKey: %s", err)
+	}
+
+	c := &Config{
+		SSHCAPrivateKey:        caPath,
+		SSHCASigningPrincipals: []string{"packer"},
+	}
+	c.sshAuthorizedKey = string(ssh.MarshalAuthorizedKey(hostPublicKey))
+
+	if err := signSshAuthorizedKey(c); err != nil {
+		t.Fatalf("signSshAuthorizedKey failed: %s", err)
+	}
+
+	parsed, _, _, _, err := ssh.ParseAuthorizedKey([]byte(c.sshAuthorizedKey))
+	if err != nil {
+		t.Fatalf("signed key does not parse as an authorized key: %s", err)
+	}
+
+	cert, ok := parsed.(*ssh.Certificate)
+	if !ok {
+		t.Fatalf("signSshAuthorizedKey did not replace the public key with a certificate")
+	}
+
+	if cert.CertType != ssh.UserCert {
+		t.Errorf("expected a user certificate, got cert type %d", cert.CertType)
+	}
+
+	if len(cert.ValidPrincipals) != 1 || cert.ValidPrincipals[0] != "packer" {
+		t.Errorf("expected ValidPrincipals [packer], got %v", cert.ValidPrincipals)
+	}
+
+	checker := ssh.CertChecker{
+		IsUserAuthority: func(auth ssh.PublicKey) bool {
+			return string(auth.Marshal()) == string(caSigner.PublicKey().Marshal())
+		},
+	}
+	if !checker.IsUserAuthority(cert.SignatureKey) {
+		t.Errorf("certificate was not signed by the test CA")
+	}
+	if err := checker.CheckCert("packer", cert); err != nil {
+		t.Errorf("certificate did not verify against the signing CA: %s", err)
+	}
+}
+
+func TestSignSshAuthorizedKey_invalidCAPath(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %s", err)
+	}
+	publicKey, err := ssh.NewPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to convert key to an ssh.PublicKey: %s", err)
+	}
+
+	// A well-formed public key, so the error comes from reading the CA key
+	// rather than from parsing sshAuthorizedKey.
+	c := &Config{SSHCAPrivateKey: "/nonexistent/ca-key"}
+	c.sshAuthorizedKey = string(ssh.MarshalAuthorizedKey(publicKey))
+
+	if err := signSshAuthorizedKey(c); err == nil {
+		t.Fatal("expected an error reading a nonexistent ssh_ca_private_key, got nil")
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See the LICENSE file in builder/azure for license information.
+
+package arm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/arm/compute"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/mitchellh/multistep"
+
+	"github.com/hashicorp/packer/builder/azure/common/constants"
+	"github.com/hashicorp/packer/packer"
+)
+
+// StepCaptureManagedImage captures the temp build VM into a managed image,
+// which StepPublishToSharedImageGallery then replicates into the Shared
+// Image Gallery destination, if one is configured.
+type StepCaptureManagedImage struct {
+	client  *AzureClient
+	capture func(ctx context.Context, resourceGroupName, imageName string, image *compute.Image) (string, error)
+	say     func(message string)
+	error   func(e error)
+}
+
+func NewStepCaptureManagedImage(client *AzureClient, ui packer.Ui) *StepCaptureManagedImage {
+	var step = &StepCaptureManagedImage{
+		client: client,
+		say:    func(message string) { ui.Say(message) },
+		error:  func(e error) { ui.Error(e.Error()) },
+	}
+
+	step.capture = step.captureManagedImage
+	return step
+}
+
+func (s *StepCaptureManagedImage) captureManagedImage(ctx context.Context, resourceGroupName, imageName string, image *compute.Image) (string, error) {
+	f, err := s.client.ImagesClient.CreateOrUpdate(ctx, resourceGroupName, imageName, *image)
+	if err != nil {
+		return "", err
+	}
+
+	if err := f.WaitForCompletionRef(ctx, s.client.ImagesClient.Client); err != nil {
+		return "", err
+	}
+
+	createdImage, err := f.Result(s.client.ImagesClient)
+	if err != nil {
+		return "", err
+	}
+
+	return to.String(createdImage.ID), nil
+}
+
+func (s *StepCaptureManagedImage) Run(ctx context.Context, stateBag multistep.StateBag) multistep.StepAction {
+	var config = stateBag.Get(constants.ConfigKey).(*Config)
+
+	if !config.isManagedImage() {
+		return multistep.ActionContinue
+	}
+
+	s.say("Capturing the managed image ...")
+
+	imageID, err := s.capture(ctx, config.ManagedImageResourceGroupName, config.ManagedImageName, config.toImageParameters())
+	if err != nil {
+		stateBag.Put(constants.Error, err)
+		s.error(fmt.Errorf("error capturing the managed image: %s", err))
+		return multistep.ActionHalt
+	}
+
+	stateBag.Put(constants.ArmManagedImageResourceID, imageID)
+	return multistep.ActionContinue
+}
+
+func (*StepCaptureManagedImage) Cleanup(multistep.StateBag) {
+	// The managed image lives in the user's own resource group, not the temp
+	// resource group, so it is never torn down on failure.
+}
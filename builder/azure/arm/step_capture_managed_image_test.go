@@ -0,0 +1,88 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See the LICENSE file in builder/azure for license information.
+
+package arm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/arm/compute"
+	"github.com/mitchellh/multistep"
+
+	"github.com/hashicorp/packer/builder/azure/common/constants"
+)
+
+func newTestStepCaptureManagedImage() *StepCaptureManagedImage {
+	return &StepCaptureManagedImage{
+		say:   func(string) {},
+		error: func(error) {},
+	}
+}
+
+func TestStepCaptureManagedImage_Run_notConfigured(t *testing.T) {
+	step := newTestStepCaptureManagedImage()
+	step.capture = func(ctx context.Context, resourceGroupName, imageName string, image *compute.Image) (string, error) {
+		t.Fatal("capture should not be called when managed_image_name is not configured")
+		return "", nil
+	}
+
+	state := new(multistep.BasicStateBag)
+	state.Put(constants.ConfigKey, &Config{})
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("expected ActionContinue, got %v", action)
+	}
+}
+
+func TestStepCaptureManagedImage_Run(t *testing.T) {
+	step := newTestStepCaptureManagedImage()
+
+	var gotResourceGroup, gotImageName string
+	step.capture = func(ctx context.Context, resourceGroupName, imageName string, image *compute.Image) (string, error) {
+		gotResourceGroup = resourceGroupName
+		gotImageName = imageName
+		return "/subscriptions/x/resourceGroups/y/providers/Microsoft.Compute/images/z", nil
+	}
+
+	config := &Config{}
+	config.ManagedImageResourceGroupName = "packer-rg"
+	config.ManagedImageName = "packer-image"
+
+	state := new(multistep.BasicStateBag)
+	state.Put(constants.ConfigKey, config)
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("expected ActionContinue, got %v", action)
+	}
+
+	if gotResourceGroup != "packer-rg" || gotImageName != "packer-image" {
+		t.Errorf("capture called with (%q, %q), want (packer-rg, packer-image)", gotResourceGroup, gotImageName)
+	}
+
+	imageID, ok := state.GetOk(constants.ArmManagedImageResourceID)
+	if !ok || imageID.(string) == "" {
+		t.Error("expected ArmManagedImageResourceID to be set in state")
+	}
+}
+
+func TestStepCaptureManagedImage_Run_captureError(t *testing.T) {
+	step := newTestStepCaptureManagedImage()
+	step.capture = func(ctx context.Context, resourceGroupName, imageName string, image *compute.Image) (string, error) {
+		return "", errors.New("boom")
+	}
+
+	config := &Config{}
+	config.ManagedImageName = "packer-image"
+
+	state := new(multistep.BasicStateBag)
+	state.Put(constants.ConfigKey, config)
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("expected ActionHalt on capture error, got %v", action)
+	}
+	if _, ok := state.GetOk(constants.Error); !ok {
+		t.Error("expected an error to be recorded in state")
+	}
+}
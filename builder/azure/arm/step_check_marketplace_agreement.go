@@ -0,0 +1,96 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See the LICENSE file in builder/azure for license information.
+
+package arm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mitchellh/multistep"
+
+	"github.com/hashicorp/packer/builder/azure/common/constants"
+	"github.com/hashicorp/packer/packer"
+)
+
+// StepCheckMarketplaceAgreement checks, and optionally accepts, the legal
+// terms for a Marketplace image referenced via plan_info. Azure rejects the
+// VM deployment with an opaque error if these terms have not been accepted
+// for the subscription, so Packer checks them up front and fails fast with
+// a clear message unless plan_info.auto_accept is set.
+type StepCheckMarketplaceAgreement struct {
+	client *AzureClient
+	check  func(ctx context.Context, subscriptionID string, plan PlanInformation) (accepted bool, err error)
+	accept func(ctx context.Context, subscriptionID string, plan PlanInformation) error
+	say    func(message string)
+	error  func(e error)
+}
+
+func NewStepCheckMarketplaceAgreement(client *AzureClient, ui packer.Ui) *StepCheckMarketplaceAgreement {
+	var step = &StepCheckMarketplaceAgreement{
+		client: client,
+		say:    func(message string) { ui.Say(message) },
+		error:  func(e error) { ui.Error(e.Error()) },
+	}
+
+	step.check = step.checkAgreement
+	step.accept = step.acceptAgreement
+	return step
+}
+
+func (s *StepCheckMarketplaceAgreement) checkAgreement(ctx context.Context, subscriptionID string, plan PlanInformation) (bool, error) {
+	terms, err := s.client.MarketplaceAgreementsClient.Get(ctx, plan.PlanPublisher, plan.PlanProduct, plan.PlanName)
+	if err != nil {
+		return false, err
+	}
+
+	return terms.Accepted != nil && *terms.Accepted, nil
+}
+
+func (s *StepCheckMarketplaceAgreement) acceptAgreement(ctx context.Context, subscriptionID string, plan PlanInformation) error {
+	_, err := s.client.MarketplaceAgreementsClient.Sign(ctx, plan.PlanPublisher, plan.PlanProduct, plan.PlanName)
+	return err
+}
+
+func (s *StepCheckMarketplaceAgreement) Run(ctx context.Context, stateBag multistep.StateBag) multistep.StepAction {
+	var config = stateBag.Get(constants.ConfigKey).(*Config)
+
+	if !config.hasPlanInfo() {
+		return multistep.ActionContinue
+	}
+
+	s.say("Checking Marketplace image plan agreement ...")
+
+	accepted, err := s.check(ctx, config.SubscriptionID, config.PlanInfo)
+	if err != nil {
+		stateBag.Put(constants.Error, err)
+		s.error(fmt.Errorf("error checking Marketplace plan agreement: %s", err))
+		return multistep.ActionHalt
+	}
+
+	if accepted {
+		return multistep.ActionContinue
+	}
+
+	if !config.PlanInfo.AutoAccept {
+		err := fmt.Errorf("the Marketplace plan %s/%s/%s has not been accepted for this subscription; set plan_info.auto_accept to accept it automatically, or accept it in the Azure portal",
+			config.PlanInfo.PlanPublisher, config.PlanInfo.PlanProduct, config.PlanInfo.PlanName)
+		stateBag.Put(constants.Error, err)
+		s.error(err)
+		return multistep.ActionHalt
+	}
+
+	s.say("Accepting Marketplace image plan agreement ...")
+	if err := s.accept(ctx, config.SubscriptionID, config.PlanInfo); err != nil {
+		stateBag.Put(constants.Error, err)
+		s.error(fmt.Errorf("error accepting Marketplace plan agreement: %s", err))
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
+func (*StepCheckMarketplaceAgreement) Cleanup(multistep.StateBag) {
+	// Accepting a Marketplace plan agreement is a subscription-level
+	// action with no build-scoped resource to clean up.
+}
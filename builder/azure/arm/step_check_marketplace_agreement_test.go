@@ -0,0 +1,117 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See the LICENSE file in builder/azure for license information.
+
+package arm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mitchellh/multistep"
+
+	"github.com/hashicorp/packer/builder/azure/common/constants"
+)
+
+func newTestStepCheckMarketplaceAgreement() *StepCheckMarketplaceAgreement {
+	return &StepCheckMarketplaceAgreement{
+		say:   func(string) {},
+		error: func(error) {},
+	}
+}
+
+func TestStepCheckMarketplaceAgreement_Run_noPlanInfo(t *testing.T) {
+	step := newTestStepCheckMarketplaceAgreement()
+	step.check = func(ctx context.Context, subscriptionID string, plan PlanInformation) (bool, error) {
+		t.Fatal("check should not be called when plan_info is not configured")
+		return false, nil
+	}
+
+	state := new(multistep.BasicStateBag)
+	state.Put(constants.ConfigKey, &Config{})
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("expected ActionContinue, got %v", action)
+	}
+}
+
+func TestStepCheckMarketplaceAgreement_Run_alreadyAccepted(t *testing.T) {
+	step := newTestStepCheckMarketplaceAgreement()
+	step.check = func(ctx context.Context, subscriptionID string, plan PlanInformation) (bool, error) {
+		return true, nil
+	}
+	step.accept = func(ctx context.Context, subscriptionID string, plan PlanInformation) error {
+		t.Fatal("accept should not be called when the agreement is already accepted")
+		return nil
+	}
+
+	config := &Config{}
+	config.PlanInfo = PlanInformation{PlanName: "n", PlanPublisher: "p", PlanProduct: "prod"}
+
+	state := new(multistep.BasicStateBag)
+	state.Put(constants.ConfigKey, config)
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("expected ActionContinue, got %v", action)
+	}
+}
+
+func TestStepCheckMarketplaceAgreement_Run_notAcceptedNoAutoAccept(t *testing.T) {
+	step := newTestStepCheckMarketplaceAgreement()
+	step.check = func(ctx context.Context, subscriptionID string, plan PlanInformation) (bool, error) {
+		return false, nil
+	}
+
+	config := &Config{}
+	config.PlanInfo = PlanInformation{PlanName: "n", PlanPublisher: "p", PlanProduct: "prod"}
+
+	state := new(multistep.BasicStateBag)
+	state.Put(constants.ConfigKey, config)
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("expected ActionHalt when the agreement isn't accepted and auto_accept is false, got %v", action)
+	}
+}
+
+func TestStepCheckMarketplaceAgreement_Run_autoAccepts(t *testing.T) {
+	step := newTestStepCheckMarketplaceAgreement()
+	step.check = func(ctx context.Context, subscriptionID string, plan PlanInformation) (bool, error) {
+		return false, nil
+	}
+
+	accepted := false
+	step.accept = func(ctx context.Context, subscriptionID string, plan PlanInformation) error {
+		accepted = true
+		return nil
+	}
+
+	config := &Config{}
+	config.PlanInfo = PlanInformation{PlanName: "n", PlanPublisher: "p", PlanProduct: "prod", AutoAccept: true}
+
+	state := new(multistep.BasicStateBag)
+	state.Put(constants.ConfigKey, config)
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("expected ActionContinue, got %v", action)
+	}
+	if !accepted {
+		t.Error("expected accept to be called when auto_accept is true")
+	}
+}
+
+func TestStepCheckMarketplaceAgreement_Run_checkError(t *testing.T) {
+	step := newTestStepCheckMarketplaceAgreement()
+	step.check = func(ctx context.Context, subscriptionID string, plan PlanInformation) (bool, error) {
+		return false, errors.New("boom")
+	}
+
+	config := &Config{}
+	config.PlanInfo = PlanInformation{PlanName: "n", PlanPublisher: "p", PlanProduct: "prod"}
+
+	state := new(multistep.BasicStateBag)
+	state.Put(constants.ConfigKey, config)
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("expected ActionHalt on check error, got %v", action)
+	}
+}
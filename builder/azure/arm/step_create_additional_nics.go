@@ -0,0 +1,118 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See the LICENSE file in builder/azure for license information.
+
+package arm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/arm/network"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/mitchellh/multistep"
+
+	"github.com/hashicorp/packer/builder/azure/common/constants"
+	"github.com/hashicorp/packer/packer"
+)
+
+// StepCreateAdditionalNics provisions the extra network interfaces
+// requested via additional_network_interfaces, in addition to the single
+// default NIC the template step attaches to the temp build VM.
+type StepCreateAdditionalNics struct {
+	client *AzureClient
+	create func(ctx context.Context, resourceGroupName, location, nicName, subnetID, nsgID string, nic NicSpec) (string, error)
+	say    func(message string)
+	error  func(e error)
+}
+
+func NewStepCreateAdditionalNics(client *AzureClient, ui packer.Ui) *StepCreateAdditionalNics {
+	var step = &StepCreateAdditionalNics{
+		client: client,
+		say:    func(message string) { ui.Say(message) },
+		error:  func(e error) { ui.Error(e.Error()) },
+	}
+
+	step.create = step.createNic
+	return step
+}
+
+func (s *StepCreateAdditionalNics) createNic(ctx context.Context, resourceGroupName, location, nicName, subnetID, nsgID string, nic NicSpec) (string, error) {
+	interfaceParams := network.Interface{
+		Location: to.StringPtr(location),
+		InterfacePropertiesFormat: &network.InterfacePropertiesFormat{
+			EnableAcceleratedNetworking: to.BoolPtr(nic.EnableAcceleratedNetworking),
+			IPConfigurations: &[]network.InterfaceIPConfiguration{
+				{
+					Name: to.StringPtr("ipconfig"),
+					InterfaceIPConfigurationPropertiesFormat: &network.InterfaceIPConfigurationPropertiesFormat{
+						Subnet: &network.Subnet{
+							ID: to.StringPtr(subnetID),
+						},
+						PrivateIPAllocationMethod: network.Dynamic,
+					},
+				},
+			},
+		},
+	}
+
+	if nsgID != "" {
+		interfaceParams.InterfacePropertiesFormat.NetworkSecurityGroup = &network.SecurityGroup{
+			ID: to.StringPtr(nsgID),
+		}
+	}
+
+	f, err := s.client.InterfacesClient.CreateOrUpdate(ctx, resourceGroupName, nicName, interfaceParams)
+	if err != nil {
+		return "", err
+	}
+
+	if err := f.WaitForCompletionRef(ctx, s.client.InterfacesClient.Client); err != nil {
+		return "", err
+	}
+
+	createdNic, err := f.Result(s.client.InterfacesClient)
+	if err != nil {
+		return "", err
+	}
+
+	return to.String(createdNic.ID), nil
+}
+
+func (s *StepCreateAdditionalNics) Run(ctx context.Context, stateBag multistep.StateBag) multistep.StepAction {
+	var config = stateBag.Get(constants.ConfigKey).(*Config)
+
+	if len(config.AdditionalNetworkInterfaces) == 0 {
+		return multistep.ActionContinue
+	}
+
+	s.say("Creating additional network interfaces ...")
+
+	nicIDs := make([]string, 0, len(config.AdditionalNetworkInterfaces))
+
+	for i, nic := range config.AdditionalNetworkInterfaces {
+		nicName := fmt.Sprintf("%s-nic-%d", config.tmpComputeName, i+1)
+		subnetID := config.toSubnetID(nic.SubnetName)
+
+		var nsgID string
+		if nic.NetworkSecurityGroup != "" {
+			nsgID = config.toNetworkSecurityGroupID(nic.NetworkSecurityGroup)
+		}
+
+		nicID, err := s.create(ctx, config.tmpResourceGroupName, config.Location, nicName, subnetID, nsgID, nic)
+		if err != nil {
+			stateBag.Put(constants.Error, err)
+			s.error(fmt.Errorf("error creating additional network interface %q: %s", nicName, err))
+			return multistep.ActionHalt
+		}
+
+		nicIDs = append(nicIDs, nicID)
+	}
+
+	stateBag.Put(constants.ArmAdditionalNicIDs, nicIDs)
+	return multistep.ActionContinue
+}
+
+func (*StepCreateAdditionalNics) Cleanup(multistep.StateBag) {
+	// The additional NICs live in the temp resource group and are not
+	// deleted here; no step in Builder.Run deletes that resource group either.
+}
@@ -0,0 +1,120 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See the LICENSE file in builder/azure for license information.
+
+package arm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mitchellh/multistep"
+
+	"github.com/hashicorp/packer/builder/azure/common/constants"
+)
+
+func newTestStepCreateAdditionalNics() *StepCreateAdditionalNics {
+	return &StepCreateAdditionalNics{
+		say:   func(string) {},
+		error: func(error) {},
+	}
+}
+
+func TestStepCreateAdditionalNics_Run_noNics(t *testing.T) {
+	step := newTestStepCreateAdditionalNics()
+	step.create = func(ctx context.Context, resourceGroupName, location, nicName, subnetID, nsgID string, nic NicSpec) (string, error) {
+		t.Fatal("create should not be called when no additional NICs are configured")
+		return "", nil
+	}
+
+	state := new(multistep.BasicStateBag)
+	state.Put(constants.ConfigKey, &Config{})
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("expected ActionContinue, got %v", action)
+	}
+}
+
+func TestStepCreateAdditionalNics_Run_resolvesSubnetAndNsg(t *testing.T) {
+	step := newTestStepCreateAdditionalNics()
+
+	var gotSubnetID, gotNsgID string
+	step.create = func(ctx context.Context, resourceGroupName, location, nicName, subnetID, nsgID string, nic NicSpec) (string, error) {
+		gotSubnetID = subnetID
+		gotNsgID = nsgID
+		return "/subscriptions/x/.../networkInterfaces/" + nicName, nil
+	}
+
+	config := &Config{}
+	config.SubscriptionID = "sub-id"
+	config.ResourceGroupName = "packer-rg"
+	config.VirtualNetworkName = "my-vnet"
+	config.tmpResourceGroupName = "packer-tmp-rg"
+	config.tmpComputeName = "packer-vm"
+	config.AdditionalNetworkInterfaces = []NicSpec{
+		{SubnetName: "my-subnet", NetworkSecurityGroup: "my-nsg"},
+	}
+
+	state := new(multistep.BasicStateBag)
+	state.Put(constants.ConfigKey, config)
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("expected ActionContinue, got %v", action)
+	}
+
+	wantSubnetID := config.toSubnetID("my-subnet")
+	if gotSubnetID != wantSubnetID {
+		t.Errorf("subnetID = %q, want %q", gotSubnetID, wantSubnetID)
+	}
+
+	wantNsgID := config.toNetworkSecurityGroupID("my-nsg")
+	if gotNsgID != wantNsgID {
+		t.Errorf("nsgID = %q, want %q", gotNsgID, wantNsgID)
+	}
+
+	nicIDs, ok := state.GetOk(constants.ArmAdditionalNicIDs)
+	if !ok || len(nicIDs.([]string)) != 1 {
+		t.Error("expected ArmAdditionalNicIDs with one entry in state")
+	}
+}
+
+func TestStepCreateAdditionalNics_Run_noNsgConfigured(t *testing.T) {
+	step := newTestStepCreateAdditionalNics()
+
+	var gotNsgID string
+	step.create = func(ctx context.Context, resourceGroupName, location, nicName, subnetID, nsgID string, nic NicSpec) (string, error) {
+		gotNsgID = nsgID
+		return "/subscriptions/x/.../networkInterfaces/" + nicName, nil
+	}
+
+	config := &Config{}
+	config.AdditionalNetworkInterfaces = []NicSpec{{SubnetName: "my-subnet"}}
+
+	state := new(multistep.BasicStateBag)
+	state.Put(constants.ConfigKey, config)
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("expected ActionContinue, got %v", action)
+	}
+
+	if gotNsgID != "" {
+		t.Errorf("expected an empty nsgID when network_security_group is unset, got %q", gotNsgID)
+	}
+}
+
+func TestStepCreateAdditionalNics_Run_createError(t *testing.T) {
+	step := newTestStepCreateAdditionalNics()
+	step.create = func(ctx context.Context, resourceGroupName, location, nicName, subnetID, nsgID string, nic NicSpec) (string, error) {
+		return "", errors.New("boom")
+	}
+
+	config := &Config{}
+	config.AdditionalNetworkInterfaces = []NicSpec{{SubnetName: "my-subnet"}}
+
+	state := new(multistep.BasicStateBag)
+	state.Put(constants.ConfigKey, config)
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("expected ActionHalt on create error, got %v", action)
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See the LICENSE file in builder/azure for license information.
+
+package arm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/arm/network"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/mitchellh/multistep"
+
+	"github.com/hashicorp/packer/builder/azure/common/constants"
+	"github.com/hashicorp/packer/packer"
+)
+
+// StepCreatePrimaryNic provisions the primary network interface that
+// StepDeployTemplate attaches to the temp build VM at the resource ID
+// predicted by Config.toNicID, passing enable_accelerated_networking through
+// as configured. The step does not check vm_size itself; an unsupported
+// vm_size is rejected earlier by assertRequiredParametersSet.
+type StepCreatePrimaryNic struct {
+	client *AzureClient
+	create func(ctx context.Context, resourceGroupName, location, nicName, subnetID string, enableAcceleratedNetworking bool) (string, error)
+	say    func(message string)
+	error  func(e error)
+}
+
+func NewStepCreatePrimaryNic(client *AzureClient, ui packer.Ui) *StepCreatePrimaryNic {
+	var step = &StepCreatePrimaryNic{
+		client: client,
+		say:    func(message string) { ui.Say(message) },
+		error:  func(e error) { ui.Error(e.Error()) },
+	}
+
+	step.create = step.createNic
+	return step
+}
+
+func (s *StepCreatePrimaryNic) createNic(ctx context.Context, resourceGroupName, location, nicName, subnetID string, enableAcceleratedNetworking bool) (string, error) {
+	interfaceParams := network.Interface{
+		Location: to.StringPtr(location),
+		InterfacePropertiesFormat: &network.InterfacePropertiesFormat{
+			EnableAcceleratedNetworking: to.BoolPtr(enableAcceleratedNetworking),
+			IPConfigurations: &[]network.InterfaceIPConfiguration{
+				{
+					Name: to.StringPtr("ipconfig"),
+					InterfaceIPConfigurationPropertiesFormat: &network.InterfaceIPConfigurationPropertiesFormat{
+						Subnet: &network.Subnet{
+							ID: to.StringPtr(subnetID),
+						},
+						PrivateIPAllocationMethod: network.Dynamic,
+					},
+				},
+			},
+		},
+	}
+
+	f, err := s.client.InterfacesClient.CreateOrUpdate(ctx, resourceGroupName, nicName, interfaceParams)
+	if err != nil {
+		return "", err
+	}
+
+	if err := f.WaitForCompletionRef(ctx, s.client.InterfacesClient.Client); err != nil {
+		return "", err
+	}
+
+	createdNic, err := f.Result(s.client.InterfacesClient)
+	if err != nil {
+		return "", err
+	}
+
+	return to.String(createdNic.ID), nil
+}
+
+func (s *StepCreatePrimaryNic) Run(ctx context.Context, stateBag multistep.StateBag) multistep.StepAction {
+	var config = stateBag.Get(constants.ConfigKey).(*Config)
+
+	s.say("Creating the primary network interface ...")
+
+	nicName := config.tmpComputeName + "nic"
+	subnetID := config.toSubnetID(config.VirtualNetworkSubnetName)
+
+	nicID, err := s.create(ctx, config.tmpResourceGroupName, config.Location, nicName, subnetID, config.EnableAcceleratedNetworking)
+	if err != nil {
+		stateBag.Put(constants.Error, err)
+		s.error(fmt.Errorf("error creating the primary network interface: %s", err))
+		return multistep.ActionHalt
+	}
+
+	stateBag.Put(constants.ArmPrimaryNicID, nicID)
+	return multistep.ActionContinue
+}
+
+func (*StepCreatePrimaryNic) Cleanup(multistep.StateBag) {
+	// The primary NIC lives in the temp resource group and is not deleted
+	// here; no step in Builder.Run deletes that resource group either.
+}
@@ -0,0 +1,75 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See the LICENSE file in builder/azure for license information.
+
+package arm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mitchellh/multistep"
+
+	"github.com/hashicorp/packer/builder/azure/common/constants"
+)
+
+func newTestStepCreatePrimaryNic() *StepCreatePrimaryNic {
+	return &StepCreatePrimaryNic{
+		say:   func(string) {},
+		error: func(error) {},
+	}
+}
+
+func TestStepCreatePrimaryNic_Run_appliesAcceleratedNetworking(t *testing.T) {
+	step := newTestStepCreatePrimaryNic()
+
+	var gotSubnetID string
+	var gotAccelerated bool
+	step.create = func(ctx context.Context, resourceGroupName, location, nicName, subnetID string, enableAcceleratedNetworking bool) (string, error) {
+		gotSubnetID = subnetID
+		gotAccelerated = enableAcceleratedNetworking
+		return "/subscriptions/x/.../networkInterfaces/" + nicName, nil
+	}
+
+	config := &Config{}
+	config.SubscriptionID = "sub-id"
+	config.ResourceGroupName = "packer-rg"
+	config.VirtualNetworkName = "my-vnet"
+	config.VirtualNetworkSubnetName = "my-subnet"
+	config.tmpResourceGroupName = "packer-tmp-rg"
+	config.tmpComputeName = "packer-vm"
+	config.EnableAcceleratedNetworking = true
+
+	state := new(multistep.BasicStateBag)
+	state.Put(constants.ConfigKey, config)
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("expected ActionContinue, got %v", action)
+	}
+
+	if wantSubnetID := config.toSubnetID("my-subnet"); gotSubnetID != wantSubnetID {
+		t.Errorf("subnetID = %q, want %q", gotSubnetID, wantSubnetID)
+	}
+	if !gotAccelerated {
+		t.Error("expected enable_accelerated_networking to reach the primary NIC create call")
+	}
+
+	nicID, ok := state.GetOk(constants.ArmPrimaryNicID)
+	if !ok || nicID.(string) == "" {
+		t.Error("expected ArmPrimaryNicID to be set in state")
+	}
+}
+
+func TestStepCreatePrimaryNic_Run_createError(t *testing.T) {
+	step := newTestStepCreatePrimaryNic()
+	step.create = func(ctx context.Context, resourceGroupName, location, nicName, subnetID string, enableAcceleratedNetworking bool) (string, error) {
+		return "", errors.New("boom")
+	}
+
+	state := new(multistep.BasicStateBag)
+	state.Put(constants.ConfigKey, &Config{})
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("expected ActionHalt on create error, got %v", action)
+	}
+}
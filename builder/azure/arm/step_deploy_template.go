@@ -0,0 +1,84 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See the LICENSE file in builder/azure for license information.
+
+package arm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/arm/compute"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/mitchellh/multistep"
+
+	"github.com/hashicorp/packer/builder/azure/common/constants"
+	"github.com/hashicorp/packer/packer"
+)
+
+// StepDeployTemplate creates the temp build VM, wiring in whichever of the
+// optional source image, Marketplace plan, and network features the user
+// configured on Config.
+type StepDeployTemplate struct {
+	client *AzureClient
+	deploy func(ctx context.Context, resourceGroupName, vmName string, vm *compute.VirtualMachine) (string, error)
+	say    func(message string)
+	error  func(e error)
+}
+
+func NewStepDeployTemplate(client *AzureClient, ui packer.Ui) *StepDeployTemplate {
+	var step = &StepDeployTemplate{
+		client: client,
+		say:    func(message string) { ui.Say(message) },
+		error:  func(e error) { ui.Error(e.Error()) },
+	}
+
+	step.deploy = step.deployTemplate
+	return step
+}
+
+func (s *StepDeployTemplate) deployTemplate(ctx context.Context, resourceGroupName, vmName string, vm *compute.VirtualMachine) (string, error) {
+	f, err := s.client.VirtualMachinesClient.CreateOrUpdate(ctx, resourceGroupName, vmName, *vm)
+	if err != nil {
+		return "", err
+	}
+
+	err = f.WaitForCompletionRef(ctx, s.client.VirtualMachinesClient.Client)
+	if err != nil {
+		return "", err
+	}
+
+	createdVM, err := f.Result(s.client.VirtualMachinesClient)
+	if err != nil {
+		return "", err
+	}
+
+	return to.String(createdVM.ID), nil
+}
+
+func (s *StepDeployTemplate) Run(ctx context.Context, stateBag multistep.StateBag) multistep.StepAction {
+	s.say("Deploying the temp build VM ...")
+
+	var config = stateBag.Get(constants.ConfigKey).(*Config)
+
+	var additionalNicIDs []string
+	if raw, ok := stateBag.GetOk(constants.ArmAdditionalNicIDs); ok {
+		additionalNicIDs = raw.([]string)
+	}
+
+	vm := config.toVirtualMachine(additionalNicIDs)
+
+	vmID, err := s.deploy(ctx, config.tmpResourceGroupName, config.tmpComputeName, vm)
+	if err != nil {
+		stateBag.Put(constants.Error, err)
+		s.error(fmt.Errorf("error deploying the temp build VM: %s", err))
+		return multistep.ActionHalt
+	}
+
+	stateBag.Put(constants.ArmComputeResourceID, vmID)
+	return multistep.ActionContinue
+}
+
+func (*StepDeployTemplate) Cleanup(multistep.StateBag) {
+	// The temp build VM lives in the temp resource group and is not deleted
+	// here; no step in Builder.Run deletes that resource group either.
+}
@@ -0,0 +1,71 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See the LICENSE file in builder/azure for license information.
+
+package arm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/arm/compute"
+	"github.com/mitchellh/multistep"
+
+	"github.com/hashicorp/packer/builder/azure/common/constants"
+)
+
+func newTestStepDeployTemplate() *StepDeployTemplate {
+	return &StepDeployTemplate{
+		say:   func(string) {},
+		error: func(error) {},
+	}
+}
+
+func TestStepDeployTemplate_Run(t *testing.T) {
+	step := newTestStepDeployTemplate()
+
+	var gotResourceGroup, gotVMName string
+	step.deploy = func(ctx context.Context, resourceGroupName, vmName string, vm *compute.VirtualMachine) (string, error) {
+		gotResourceGroup = resourceGroupName
+		gotVMName = vmName
+		return "/subscriptions/x/resourceGroups/y/providers/Microsoft.Compute/virtualMachines/z", nil
+	}
+
+	config := &Config{}
+	config.tmpResourceGroupName = "packer-rg"
+	config.tmpComputeName = "packer-vm"
+
+	state := new(multistep.BasicStateBag)
+	state.Put(constants.ConfigKey, config)
+	state.Put(constants.ArmAdditionalNicIDs, []string{"/subscriptions/x/.../nic-1"})
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("expected ActionContinue, got %v", action)
+	}
+
+	if gotResourceGroup != "packer-rg" || gotVMName != "packer-vm" {
+		t.Errorf("deploy called with (%q, %q), want (packer-rg, packer-vm)", gotResourceGroup, gotVMName)
+	}
+
+	vmID, ok := state.GetOk(constants.ArmComputeResourceID)
+	if !ok || vmID.(string) == "" {
+		t.Error("expected ArmComputeResourceID to be set in state")
+	}
+}
+
+func TestStepDeployTemplate_Run_deployError(t *testing.T) {
+	step := newTestStepDeployTemplate()
+	step.deploy = func(ctx context.Context, resourceGroupName, vmName string, vm *compute.VirtualMachine) (string, error) {
+		return "", errors.New("boom")
+	}
+
+	state := new(multistep.BasicStateBag)
+	state.Put(constants.ConfigKey, &Config{})
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("expected ActionHalt on deploy error, got %v", action)
+	}
+	if _, ok := state.GetOk(constants.Error); !ok {
+		t.Error("expected an error to be recorded in state")
+	}
+}
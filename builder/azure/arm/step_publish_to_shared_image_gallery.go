@@ -0,0 +1,136 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See the LICENSE file in builder/azure for license information.
+
+package arm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/arm/compute"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/mitchellh/multistep"
+
+	"github.com/hashicorp/packer/builder/azure/common/constants"
+	"github.com/hashicorp/packer/packer"
+)
+
+// StepPublishToSharedImageGallery replicates the managed image produced by
+// the build into a Shared Image Gallery image version, once the managed
+// image itself has been created.
+type StepPublishToSharedImageGallery struct {
+	client  *AzureClient
+	publish func(ctx context.Context, resourceGroupName string, destination SharedImageGalleryDestination, location string, tags map[string]*string, managedImageID string) (string, error)
+	say     func(message string)
+	error   func(e error)
+}
+
+func NewStepPublishToSharedImageGallery(client *AzureClient, ui packer.Ui) *StepPublishToSharedImageGallery {
+	var step = &StepPublishToSharedImageGallery{
+		client: client,
+		say:    func(message string) { ui.Say(message) },
+		error:  func(e error) { ui.Error(e.Error()) },
+	}
+
+	step.publish = step.publishToSharedImageGallery
+	return step
+}
+
+func (s *StepPublishToSharedImageGallery) publishToSharedImageGallery(ctx context.Context, resourceGroupName string, destination SharedImageGalleryDestination, location string, tags map[string]*string, managedImageID string) (string, error) {
+	galleryImageVersion := compute.GalleryImageVersion{
+		Location: to.StringPtr(location),
+		Tags:     tags,
+		GalleryImageVersionProperties: &compute.GalleryImageVersionProperties{
+			PublishingProfile: &compute.GalleryImageVersionPublishingProfile{
+				TargetRegions: toTargetRegions(destination.SigDestinationReplicationRegions, destination.SigDestinationReplicaCount),
+			},
+			StorageProfile: &compute.GalleryImageVersionStorageProfile{
+				Source: &compute.GalleryArtifactVersionSource{
+					ID: to.StringPtr(managedImageID),
+				},
+			},
+		},
+	}
+
+	// The gallery image version is created in the destination subscription,
+	// which may differ from the subscription the temp build VM lives in, so
+	// the client must be re-scoped to it rather than reusing
+	// s.client.GalleryImageVersionsClient as-is.
+	galleryImageVersionsClient := s.client.GalleryImageVersionsClient
+	galleryImageVersionsClient.SubscriptionID = destination.SigDestinationSubscription
+
+	f, err := galleryImageVersionsClient.CreateOrUpdate(
+		ctx,
+		destination.SigDestinationResourceGroup,
+		destination.SigDestinationGalleryName,
+		destination.SigDestinationImageName,
+		destination.SigDestinationImageVersion,
+		galleryImageVersion)
+	if err != nil {
+		return "", err
+	}
+
+	err = f.WaitForCompletionRef(ctx, galleryImageVersionsClient.Client)
+	if err != nil {
+		return "", err
+	}
+
+	createdVersion, err := f.Result(galleryImageVersionsClient)
+	if err != nil {
+		return "", err
+	}
+
+	return to.String(createdVersion.ID), nil
+}
+
+func toTargetRegions(regions []string, replicaCount int32) *[]compute.TargetRegion {
+	targetRegions := make([]compute.TargetRegion, len(regions))
+	for i, region := range regions {
+		targetRegions[i] = compute.TargetRegion{
+			Name:                 to.StringPtr(region),
+			RegionalReplicaCount: to.Int32Ptr(replicaCount),
+		}
+	}
+	return &targetRegions
+}
+
+func (s *StepPublishToSharedImageGallery) Run(ctx context.Context, stateBag multistep.StateBag) multistep.StepAction {
+	var config = stateBag.Get(constants.ConfigKey).(*Config)
+
+	if !config.isPublishToSharedImageGallery() {
+		return multistep.ActionContinue
+	}
+
+	managedImageID, ok := stateBag.Get(constants.ArmManagedImageResourceID).(string)
+	if !ok {
+		err := fmt.Errorf("error publishing to Shared Image Gallery: no managed image resource ID in state")
+		stateBag.Put(constants.Error, err)
+		s.error(err)
+		return multistep.ActionHalt
+	}
+
+	s.say("Publishing to Shared Image Gallery ...")
+
+	imageVersionID, err := s.publish(
+		ctx,
+		config.SharedImageGalleryDestination.SigDestinationResourceGroup,
+		config.SharedImageGalleryDestination,
+		config.Location,
+		config.AzureTags,
+		managedImageID)
+
+	if err != nil {
+		stateBag.Put(constants.Error, err)
+		s.error(fmt.Errorf("error publishing to Shared Image Gallery: %s", err))
+		return multistep.ActionHalt
+	}
+
+	stateBag.Put(constants.ArmSharedImageGalleryDestinationImageVersionID, imageVersionID)
+	return multistep.ActionContinue
+}
+
+func (*StepPublishToSharedImageGallery) Cleanup(multistep.StateBag) {
+	// Replication to a Shared Image Gallery is not undone on failure, and the
+	// managed image it was published from is left in place as well (see
+	// StepCaptureManagedImage.Cleanup).
+}
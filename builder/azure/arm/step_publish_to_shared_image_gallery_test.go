@@ -0,0 +1,102 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See the LICENSE file in builder/azure for license information.
+
+package arm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mitchellh/multistep"
+
+	"github.com/hashicorp/packer/builder/azure/common/constants"
+)
+
+func newTestStepPublishToSharedImageGallery() *StepPublishToSharedImageGallery {
+	return &StepPublishToSharedImageGallery{
+		say:   func(string) {},
+		error: func(error) {},
+	}
+}
+
+func TestStepPublishToSharedImageGallery_Run_notConfigured(t *testing.T) {
+	step := newTestStepPublishToSharedImageGallery()
+	step.publish = func(ctx context.Context, resourceGroupName string, destination SharedImageGalleryDestination, location string, tags map[string]*string, managedImageID string) (string, error) {
+		t.Fatal("publish should not be called when shared_image_gallery_destination is not configured")
+		return "", nil
+	}
+
+	state := new(multistep.BasicStateBag)
+	state.Put(constants.ConfigKey, &Config{})
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("expected ActionContinue, got %v", action)
+	}
+}
+
+func TestStepPublishToSharedImageGallery_Run_missingManagedImageID(t *testing.T) {
+	step := newTestStepPublishToSharedImageGallery()
+	step.publish = func(ctx context.Context, resourceGroupName string, destination SharedImageGalleryDestination, location string, tags map[string]*string, managedImageID string) (string, error) {
+		t.Fatal("publish should not be called when the managed image ID is missing from state")
+		return "", nil
+	}
+
+	config := &Config{}
+	config.SharedImageGalleryDestination.SigDestinationGalleryName = "my-gallery"
+
+	state := new(multistep.BasicStateBag)
+	state.Put(constants.ConfigKey, config)
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("expected ActionHalt when the managed image ID is missing, got %v", action)
+	}
+	if _, ok := state.GetOk(constants.Error); !ok {
+		t.Error("expected an error to be recorded in state")
+	}
+}
+
+func TestStepPublishToSharedImageGallery_Run_publishes(t *testing.T) {
+	step := newTestStepPublishToSharedImageGallery()
+
+	var gotManagedImageID string
+	step.publish = func(ctx context.Context, resourceGroupName string, destination SharedImageGalleryDestination, location string, tags map[string]*string, managedImageID string) (string, error) {
+		gotManagedImageID = managedImageID
+		return "/subscriptions/x/.../imageVersions/1.0.0", nil
+	}
+
+	config := &Config{}
+	config.SharedImageGalleryDestination.SigDestinationGalleryName = "my-gallery"
+
+	state := new(multistep.BasicStateBag)
+	state.Put(constants.ConfigKey, config)
+	state.Put(constants.ArmManagedImageResourceID, "/subscriptions/x/.../images/my-image")
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("expected ActionContinue, got %v", action)
+	}
+	if gotManagedImageID != "/subscriptions/x/.../images/my-image" {
+		t.Errorf("publish called with managedImageID %q", gotManagedImageID)
+	}
+	if _, ok := state.GetOk(constants.ArmSharedImageGalleryDestinationImageVersionID); !ok {
+		t.Error("expected ArmSharedImageGalleryDestinationImageVersionID to be set in state")
+	}
+}
+
+func TestStepPublishToSharedImageGallery_Run_publishError(t *testing.T) {
+	step := newTestStepPublishToSharedImageGallery()
+	step.publish = func(ctx context.Context, resourceGroupName string, destination SharedImageGalleryDestination, location string, tags map[string]*string, managedImageID string) (string, error) {
+		return "", errors.New("boom")
+	}
+
+	config := &Config{}
+	config.SharedImageGalleryDestination.SigDestinationGalleryName = "my-gallery"
+
+	state := new(multistep.BasicStateBag)
+	state.Put(constants.ConfigKey, config)
+	state.Put(constants.ArmManagedImageResourceID, "/subscriptions/x/.../images/my-image")
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("expected ActionHalt on publish error, got %v", action)
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See the LICENSE file in builder/azure for license information.
+
+package arm
+
+import (
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/arm/compute"
+	"github.com/Azure/go-autorest/autorest/to"
+
+	"github.com/hashicorp/packer/builder/azure/common/constants"
+)
+
+// toNicID returns the resource ID of the primary NIC that the template
+// deployment step attaches to the temp build VM.
+func (c *Config) toNicID() string {
+	return fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Network/networkInterfaces/%snic",
+		c.SubscriptionID, c.tmpResourceGroupName, c.tmpComputeName)
+}
+
+// toVirtualMachine builds the parameters for the temp build VM deployment,
+// wiring in whichever of the optional source/network features the user
+// configured. additionalNicIDs are the resource IDs of the extra NICs
+// created by StepCreateAdditionalNics, if any.
+func (c *Config) toVirtualMachine(additionalNicIDs []string) *compute.VirtualMachine {
+	interfaces := make([]compute.NetworkInterfaceReference, 0, len(additionalNicIDs)+1)
+	interfaces = append(interfaces, compute.NetworkInterfaceReference{
+		ID: to.StringPtr(c.toNicID()),
+		NetworkInterfaceReferenceProperties: &compute.NetworkInterfaceReferenceProperties{
+			Primary: to.BoolPtr(true),
+		},
+	})
+	for _, nicID := range additionalNicIDs {
+		interfaces = append(interfaces, compute.NetworkInterfaceReference{ID: to.StringPtr(nicID)})
+	}
+
+	vm := &compute.VirtualMachine{
+		Location: to.StringPtr(c.Location),
+		Tags:     &c.AzureTags,
+		Plan:     c.toVMPlan(),
+		VirtualMachineProperties: &compute.VirtualMachineProperties{
+			HardwareProfile: &compute.HardwareProfile{
+				VMSize: compute.VirtualMachineSizeTypes(c.VMSize),
+			},
+			NetworkProfile: &compute.NetworkProfile{
+				NetworkInterfaces: &interfaces,
+			},
+			OsProfile: c.toOSProfile(),
+		},
+	}
+
+	vm.VirtualMachineProperties.StorageProfile = c.toStorageProfile()
+
+	return vm
+}
+
+// toStorageProfile resolves whichever of the four mutually-exclusive source
+// kinds assertRequiredParametersSet validated (Shared Image Gallery, custom
+// managed image, image_url, or platform image) into the StorageProfile the
+// temp build VM is deployed from.
+func (c *Config) toStorageProfile() *compute.StorageProfile {
+	switch {
+	case c.isSharedImageGallery():
+		return &compute.StorageProfile{
+			ImageReference: &compute.ImageReference{
+				ID: to.StringPtr(c.sharedImageGalleryImageID),
+			},
+		}
+	case c.isCustomManagedImage():
+		return &compute.StorageProfile{
+			ImageReference: &compute.ImageReference{
+				ID: to.StringPtr(c.customManagedImageID),
+			},
+		}
+	case c.ImageUrl != "":
+		return &compute.StorageProfile{
+			OsDisk: &compute.OSDisk{
+				Name:         to.StringPtr(c.tmpOSDiskName),
+				OsType:       compute.OperatingSystemTypes(c.OSType),
+				CreateOption: compute.FromImage,
+				Image: &compute.VirtualHardDisk{
+					URI: to.StringPtr(c.ImageUrl),
+				},
+			},
+		}
+	default:
+		return &compute.StorageProfile{
+			ImageReference: &compute.ImageReference{
+				Publisher: to.StringPtr(c.ImagePublisher),
+				Offer:     to.StringPtr(c.ImageOffer),
+				Sku:       to.StringPtr(c.ImageSku),
+				Version:   to.StringPtr(c.ImageVersion),
+			},
+		}
+	}
+}
+
+// toOSProfile builds the OS profile for the temp build VM, injecting the
+// ephemeral (or CA-signed, if ssh_ca_private_key is set) SSH public key and
+// any custom_data_file contents so they actually reach the deployed VM.
+func (c *Config) toOSProfile() *compute.OSProfile {
+	osProfile := &compute.OSProfile{
+		ComputerName:  to.StringPtr(c.tmpComputeName),
+		AdminUsername: to.StringPtr(c.UserName),
+	}
+
+	if c.customData != "" {
+		osProfile.CustomData = to.StringPtr(c.customData)
+	}
+
+	if c.OSType == constants.Target_Linux {
+		osProfile.LinuxConfiguration = &compute.LinuxConfiguration{
+			DisablePasswordAuthentication: to.BoolPtr(true),
+			SSH: &compute.SSHConfiguration{
+				PublicKeys: &[]compute.SSHPublicKey{
+					{
+						Path:    to.StringPtr(fmt.Sprintf("/home/%s/.ssh/authorized_keys", c.UserName)),
+						KeyData: to.StringPtr(c.sshAuthorizedKey),
+					},
+				},
+			},
+		}
+	} else {
+		osProfile.AdminPassword = to.StringPtr(c.tmpAdminPassword)
+	}
+
+	return osProfile
+}
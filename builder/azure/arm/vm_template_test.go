@@ -0,0 +1,110 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See the LICENSE file in builder/azure for license information.
+
+package arm
+
+import (
+	"testing"
+
+	"github.com/hashicorp/packer/builder/azure/common/constants"
+)
+
+func TestToVirtualMachine_plan(t *testing.T) {
+	c := &Config{}
+	c.SubscriptionID = "sub-id"
+	c.tmpResourceGroupName = "packer-tmp-rg"
+	c.tmpComputeName = "packer-vm"
+	c.PlanInfo = PlanInformation{PlanName: "n", PlanPublisher: "p", PlanProduct: "prod"}
+
+	vm := c.toVirtualMachine(nil)
+
+	if vm.Plan == nil {
+		t.Fatal("expected toVirtualMachine to set Plan when plan_info is configured")
+	}
+	if *vm.Plan.Name != "n" || *vm.Plan.Publisher != "p" || *vm.Plan.Product != "prod" {
+		t.Errorf("Plan = %+v, want name=n publisher=p product=prod", vm.Plan)
+	}
+}
+
+func TestToVirtualMachine_noPlan(t *testing.T) {
+	c := &Config{}
+	c.SubscriptionID = "sub-id"
+	c.tmpResourceGroupName = "packer-tmp-rg"
+	c.tmpComputeName = "packer-vm"
+
+	vm := c.toVirtualMachine(nil)
+
+	if vm.Plan != nil {
+		t.Errorf("expected a nil Plan when plan_info is not configured, got %+v", vm.Plan)
+	}
+}
+
+func TestToVirtualMachine_sshAuthorizedKeyInjected(t *testing.T) {
+	c := &Config{}
+	c.SubscriptionID = "sub-id"
+	c.tmpResourceGroupName = "packer-tmp-rg"
+	c.tmpComputeName = "packer-vm"
+	c.UserName = "packer"
+	c.OSType = constants.Target_Linux
+	c.sshAuthorizedKey = "ssh-rsa AAAA... packer"
+
+	vm := c.toVirtualMachine(nil)
+
+	if vm.OsProfile == nil || vm.OsProfile.LinuxConfiguration == nil || vm.OsProfile.LinuxConfiguration.SSH == nil {
+		t.Fatal("expected toVirtualMachine to set a LinuxConfiguration.SSH block")
+	}
+	keys := *vm.OsProfile.LinuxConfiguration.SSH.PublicKeys
+	if len(keys) != 1 || *keys[0].KeyData != c.sshAuthorizedKey {
+		t.Errorf("PublicKeys = %+v, want the (possibly CA-signed) sshAuthorizedKey", keys)
+	}
+}
+
+func TestToVirtualMachine_storageProfileBySourceKind(t *testing.T) {
+	base := func() *Config {
+		c := &Config{}
+		c.SubscriptionID = "sub-id"
+		c.tmpResourceGroupName = "packer-tmp-rg"
+		c.tmpComputeName = "packer-vm"
+		return c
+	}
+
+	t.Run("customManagedImage", func(t *testing.T) {
+		c := base()
+		c.CustomManagedImageResourceGroupName = "img-rg"
+		c.CustomManagedImageName = "img-name"
+		setCustomManagedImageID(c)
+
+		vm := c.toVirtualMachine(nil)
+
+		if vm.StorageProfile == nil || vm.StorageProfile.ImageReference == nil || *vm.StorageProfile.ImageReference.ID != c.customManagedImageID {
+			t.Fatalf("StorageProfile = %+v, want ImageReference.ID = %s", vm.StorageProfile, c.customManagedImageID)
+		}
+	})
+
+	t.Run("imageUrl", func(t *testing.T) {
+		c := base()
+		c.ImageUrl = "https://storage.example.com/vhds/base.vhd"
+		c.OSType = constants.Target_Linux
+
+		vm := c.toVirtualMachine(nil)
+
+		if vm.StorageProfile == nil || vm.StorageProfile.OsDisk == nil || vm.StorageProfile.OsDisk.Image == nil || *vm.StorageProfile.OsDisk.Image.URI != c.ImageUrl {
+			t.Fatalf("StorageProfile = %+v, want OsDisk.Image.URI = %s", vm.StorageProfile, c.ImageUrl)
+		}
+	})
+
+	t.Run("platformImage", func(t *testing.T) {
+		c := base()
+		c.ImagePublisher = "Canonical"
+		c.ImageOffer = "UbuntuServer"
+		c.ImageSku = "18.04-LTS"
+		c.ImageVersion = "latest"
+
+		vm := c.toVirtualMachine(nil)
+
+		ref := vm.StorageProfile.ImageReference
+		if ref == nil || *ref.Publisher != c.ImagePublisher || *ref.Offer != c.ImageOffer || *ref.Sku != c.ImageSku || *ref.Version != c.ImageVersion {
+			t.Fatalf("ImageReference = %+v, want publisher/offer/sku/version from Config", ref)
+		}
+	})
+}